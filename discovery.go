@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+)
+
+const defaultClusterCacheTTL = 10 * time.Minute
+
+// clusterCache is the on-disk cache of cluster -> service-name mapping, used
+// so `--service`-only invocations don't have to re-enumerate every cluster
+// and its services on every run.
+type clusterCache struct {
+	GeneratedAt time.Time           `json:"generated_at"`
+	Clusters    map[string][]string `json:"clusters"`
+}
+
+// clusterCachePath returns the on-disk location of the cluster cache,
+// ~/.cache/docker-connector/clusters.json.
+func clusterCachePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".cache", "docker-connector", "clusters.json"), nil
+}
+
+// loadClusterCache reads the cache from disk, returning nil (not an error)
+// if it doesn't exist or has expired.
+func loadClusterCache(ttl time.Duration) (*clusterCache, error) {
+	path, err := clusterCachePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read cluster cache: %w", err)
+	}
+	var cache clusterCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("could not parse cluster cache: %w", err)
+	}
+	if time.Since(cache.GeneratedAt) > ttl {
+		return nil, nil
+	}
+	return &cache, nil
+}
+
+// saveClusterCache writes the cache to disk, creating its parent directory
+// if needed.
+func saveClusterCache(cache *clusterCache) error {
+	path, err := clusterCachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("could not create cache directory: %w", err)
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("could not marshal cluster cache: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// discoverClusterNames pages through ListClusters and returns every cluster
+// ARN in the account.
+func discoverClusterNames(svc *ecs.Client) ([]string, error) {
+	var clusters []string
+	var nextToken *string
+	for {
+		result, err := svc.ListClusters(context.TODO(), &ecs.ListClustersInput{NextToken: nextToken})
+		if err != nil {
+			return nil, fmt.Errorf("could not list clusters: %w", err)
+		}
+		clusters = append(clusters, result.ClusterArns...)
+		if result.NextToken == nil {
+			break
+		}
+		nextToken = result.NextToken
+	}
+	return clusters, nil
+}
+
+// discoverServiceNames pages through ListServices for a single cluster and
+// returns the short service names (not ARNs) running in it.
+func discoverServiceNames(svc *ecs.Client, cluster string) ([]string, error) {
+	var services []string
+	var nextToken *string
+	for {
+		result, err := svc.ListServices(context.TODO(), &ecs.ListServicesInput{
+			Cluster:   aws.String(cluster),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not list services for cluster %s: %w", cluster, err)
+		}
+		for _, arn := range result.ServiceArns {
+			services = append(services, serviceNameFromArn(arn))
+		}
+		if result.NextToken == nil {
+			break
+		}
+		nextToken = result.NextToken
+	}
+	return services, nil
+}
+
+// serviceNameFromArn extracts the short service name from a service ARN
+// (arn:aws:ecs:region:account:service/cluster/service-name).
+func serviceNameFromArn(arn string) string {
+	parts := strings.Split(arn, "/")
+	return parts[len(parts)-1]
+}
+
+// buildClusterCache enumerates every cluster in the account and the services
+// running in each, for caching.
+func buildClusterCache(svc *ecs.Client) (*clusterCache, error) {
+	clusterArns, err := discoverClusterNames(svc)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &clusterCache{Clusters: map[string][]string{}}
+	for _, clusterArn := range clusterArns {
+		clusterName := serviceNameFromArn(clusterArn)
+		services, err := discoverServiceNames(svc, clusterArn)
+		if err != nil {
+			return nil, err
+		}
+		cache.Clusters[clusterName] = services
+	}
+	return cache, nil
+}
+
+// resolveClusterForService finds which cluster(s) run serviceName (supports
+// a glob pattern, e.g. "web-*"), using the on-disk cache when fresh. It
+// rebuilds and re-caches on a miss or when refresh is set, and prompts for
+// disambiguation if more than one cluster matches.
+func resolveClusterForService(svc *ecs.Client, serviceName string, refresh bool) (string, error) {
+	var cache *clusterCache
+	var err error
+	if !refresh {
+		cache, err = loadClusterCache(defaultClusterCacheTTL)
+		if err != nil {
+			return "", err
+		}
+	}
+	if cache == nil {
+		cache, err = buildClusterCache(svc)
+		if err != nil {
+			return "", err
+		}
+		cache.GeneratedAt = time.Now()
+		if err := saveClusterCache(cache); err != nil {
+			log.Printf("warning: could not write cluster cache: %v", err)
+		}
+	}
+
+	var matches []string
+	for clusterName, services := range cache.Clusters {
+		for _, svcName := range services {
+			if matched, _ := filepath.Match(serviceName, svcName); matched || svcName == serviceName {
+				matches = append(matches, clusterName)
+				break
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no cluster found running a service matching %q (try --refresh if it was just deployed)", serviceName)
+	case 1:
+		return matches[0], nil
+	default:
+		return disambiguateCluster(serviceName, matches)
+	}
+}
+
+// resolveClusterFlag returns clusterName unchanged if set, otherwise
+// auto-discovers it from serviceName via resolveClusterForService. Shared by
+// every subcommand so --cluster is optional everywhere, not just the default
+// connect flow.
+func resolveClusterFlag(svc *ecs.Client, clusterName, serviceName string, refresh bool) (string, error) {
+	if clusterName != "" {
+		return clusterName, nil
+	}
+	discovered, err := resolveClusterForService(svc, serviceName, refresh)
+	if err != nil {
+		return "", fmt.Errorf("auto-discovering cluster for service %s: %w", serviceName, err)
+	}
+	log.Printf("Auto-discovered cluster %s for service %s\n", discovered, serviceName)
+	return discovered, nil
+}
+
+// disambiguateCluster prompts the user to choose among clusters that all run
+// a matching service.
+func disambiguateCluster(serviceName string, clusters []string) (string, error) {
+	fmt.Printf("Service %q matches in more than one cluster:\n", serviceName)
+	for i, c := range clusters {
+		fmt.Printf("  [%d] %s\n", i+1, c)
+	}
+	fmt.Print("Select a cluster number: ")
+
+	reader := bufio.NewReader(os.Stdin)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("error reading selection: %w", err)
+	}
+	idx, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || idx < 1 || idx > len(clusters) {
+		return "", fmt.Errorf("invalid selection %q", strings.TrimSpace(line))
+	}
+	return clusters[idx-1], nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestServiceNameFromArn(t *testing.T) {
+	cases := map[string]string{
+		"arn:aws:ecs:eu-west-2:123456789012:service/my-cluster/web-service": "web-service",
+		"arn:aws:ecs:eu-west-2:123456789012:cluster/my-cluster":             "my-cluster",
+		"bare-name":                                                        "bare-name",
+	}
+	for arn, want := range cases {
+		if got := serviceNameFromArn(arn); got != want {
+			t.Errorf("serviceNameFromArn(%q) = %q, want %q", arn, got, want)
+		}
+	}
+}
+
+// TestClusterGlobMatching exercises the filepath.Match-based glob matching
+// resolveClusterForService uses to match a --service pattern like "web-*"
+// against the cached service names for a cluster.
+func TestClusterGlobMatching(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"web-*", "web-service", true},
+		{"web-*", "worker-service", false},
+		{"web-service", "web-service", true},
+		{"*-service", "web-service", true},
+	}
+	for _, c := range cases {
+		matched, err := filepath.Match(c.pattern, c.name)
+		if err != nil {
+			t.Fatalf("filepath.Match(%q, %q) error: %v", c.pattern, c.name, err)
+		}
+		if matched != c.want {
+			t.Errorf("filepath.Match(%q, %q) = %v, want %v", c.pattern, c.name, matched, c.want)
+		}
+	}
+}
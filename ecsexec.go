@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/ykag/docker-connector/retry"
+)
+
+// execAgentStatus looks up the ExecuteCommandAgent managed-agent status for
+// the named container within a described task, so callers can surface a
+// useful error instead of letting the session-manager-plugin fail opaquely.
+func execAgentStatus(task types.Task, containerName string) (status string, found bool) {
+	for _, container := range task.Containers {
+		if container.Name == nil || *container.Name != containerName {
+			continue
+		}
+		for _, agent := range container.ManagedAgents {
+			if agent.Name == types.ManagedAgentNameExecuteCommandAgent {
+				return aws.ToString(agent.LastStatus), true
+			}
+		}
+	}
+	return "", false
+}
+
+// execTarget builds the SSM target string expected by the
+// session-manager-plugin for an ECS Exec session.
+func execTarget(clusterName, taskID, runtimeID string) string {
+	return fmt.Sprintf("ecs:%s_%s_%s", clusterName, taskID, runtimeID)
+}
+
+// startECSExecSession runs the given command inside containerName on task
+// via ecs.ExecuteCommand, then hands the returned session off to the
+// session-manager-plugin binary. This works for both EC2 and Fargate tasks
+// and does not require a docker daemon on the host, unlike startSSMSession.
+// task must already have been described by the caller (e.g. via
+// resolveTask), so this does not re-describe it just to check agent status.
+func startECSExecSession(svc *ecs.Client, clusterName string, task types.Task, runtimeID, containerName, command, region string, interactive bool) error {
+	taskArn := aws.ToString(task.TaskArn)
+
+	if status, found := execAgentStatus(task, containerName); found && status == "DISABLED" {
+		return retry.Terminal(fmt.Errorf("ExecuteCommandAgent is DISABLED for container %q; enable it with --enable-execute-command on the service/task definition and redeploy", containerName))
+	} else if found && status != "RUNNING" {
+		return fmt.Errorf("%w: ExecuteCommandAgent status is %s for container %q", ErrAgentNotReady, status, containerName)
+	}
+
+	input := &ecs.ExecuteCommandInput{
+		Cluster:     aws.String(clusterName),
+		Task:        aws.String(taskArn),
+		Container:   aws.String(containerName),
+		Command:     aws.String(command),
+		Interactive: interactive,
+	}
+	result, err := svc.ExecuteCommand(context.TODO(), input)
+	if err != nil {
+		return fmt.Errorf("ecs:ExecuteCommand failed: %w", err)
+	}
+
+	sessionJSON, err := sessionManagerPluginArgs(result)
+	if err != nil {
+		return err
+	}
+
+	target := execTarget(clusterName, taskIDFromArn(taskArn), runtimeID)
+	cmd := sessionManagerPluginCmd(sessionJSON, region, target)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+// sessionManagerPluginArgs marshals the ExecuteCommand session into the JSON
+// blob the session-manager-plugin expects as its first positional argument.
+func sessionManagerPluginArgs(result *ecs.ExecuteCommandOutput) (string, error) {
+	sessionJSON, err := json.Marshal(result.Session)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal ECS Exec session: %w", err)
+	}
+	return string(sessionJSON), nil
+}
+
+// sessionManagerPluginCmd builds the session-manager-plugin invocation shared
+// by the interactive exec path and the cp subsystem.
+func sessionManagerPluginCmd(sessionJSON, region, target string) *exec.Cmd {
+	return exec.Command("session-manager-plugin", sessionJSON, region, "StartSession", "", target, "")
+}
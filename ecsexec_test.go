@@ -0,0 +1,11 @@
+package main
+
+import "testing"
+
+func TestExecTarget(t *testing.T) {
+	got := execTarget("my-cluster", "abc123", "runtime-xyz")
+	want := "ecs:my-cluster_abc123_runtime-xyz"
+	if got != want {
+		t.Errorf("execTarget(...) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/aws/smithy-go"
+	"github.com/ykag/docker-connector/retry"
+)
+
+// ErrNoRunningTasks indicates ECS hasn't scheduled a running task for the
+// service yet - typically transient during a deployment, so it is retried.
+var ErrNoRunningTasks = errors.New("no running tasks found for service")
+
+// ErrAgentNotReady indicates the ECS Exec or SSM agent on the target
+// container/instance hasn't come up yet - also transient, also retried.
+var ErrAgentNotReady = errors.New("exec agent is not ready yet")
+
+// ErrContainerNotFound indicates the requested container name isn't present
+// in the task - a config/usage mistake, not transient, so it is terminal.
+var ErrContainerNotFound = errors.New("container not found in task")
+
+// authErrorCodes are the AWS API error codes that indicate an auth or
+// permissions problem rather than a transient condition (e.g. throttling),
+// so they should fail fast instead of retrying for the full backoff window.
+var authErrorCodes = map[string]bool{
+	"AccessDeniedException":       true,
+	"AccessDenied":                true,
+	"UnauthorizedException":       true,
+	"UnrecognizedClientException": true,
+}
+
+// classifyAWSError marks err as retry.Terminal if it's an AWS API error with
+// an auth-class error code, leaving other AWS errors (e.g. throttling) to
+// retry as usual.
+func classifyAWSError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && authErrorCodes[apiErr.ErrorCode()] {
+		return retry.Terminal(err)
+	}
+	return err
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
 	"os/exec"
+	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -15,39 +17,11 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/ykag/docker-connector/retry"
 )
 
-func getECSTask(svc *ecs.Client, clusterName, serviceName string) (string, string, error) {
-	input := &ecs.ListTasksInput{
-		Cluster:       aws.String(clusterName),
-		ServiceName:   aws.String(serviceName),
-		DesiredStatus: types.DesiredStatusRunning,
-	}
-	result, err := svc.ListTasks(context.TODO(), input)
-	if err != nil || len(result.TaskArns) == 0 {
-		return "", "", fmt.Errorf("no running tasks found for service %s", serviceName)
-	}
-
-    if len(result.TaskArns) == 0 {
-        return "", "", fmt.Errorf("no running tasks found for service %s", serviceName)
-    }
-
-    // Randomly select a task ARN
-    rand.Seed(time.Now().UnixNano())
-    taskArn := result.TaskArns[rand.Intn(len(result.TaskArns))]
-
-	describeInput := &ecs.DescribeTasksInput{
-		Cluster: aws.String(clusterName),
-		Tasks:   []string{taskArn},
-	}
-	describeResult, err := svc.DescribeTasks(context.TODO(), describeInput)
-	if err != nil || len(describeResult.Tasks) == 0 {
-		return "", "", fmt.Errorf("could not describe the ECS task")
-	}
-	containerInstanceArn := describeResult.Tasks[0].ContainerInstanceArn
-	return taskArn, *containerInstanceArn, nil
-}
-
+// getEC2InstanceID resolves the EC2 host backing an ECS container instance,
+// for the legacy SSM-on-EC2-docker-exec path.
 func getEC2InstanceID(svc *ecs.Client, clusterName, containerInstanceArn string) (string, error) {
 	input := &ecs.DescribeContainerInstancesInput{
 		Cluster:            aws.String(clusterName),
@@ -57,14 +31,7 @@ func getEC2InstanceID(svc *ecs.Client, clusterName, containerInstanceArn string)
 	if err != nil || len(result.ContainerInstances) == 0 {
 		return "", fmt.Errorf("could not describe container instance")
 	}
-
-	if len(result.ContainerInstances) == 0 {
-        return "", fmt.Errorf("no container instances found for cluster %s", clusterName)
-    }
-
-    rand.Seed(time.Now().UnixNano())
-    selectedInstance := result.ContainerInstances[rand.Intn(len(result.ContainerInstances))]
-    return *selectedInstance.Ec2InstanceId, nil
+	return *result.ContainerInstances[0].Ec2InstanceId, nil
 }
 
 func getContainerID(svc *ecs.Client, clusterName, taskArn, containerName string) (string, error) {
@@ -81,7 +48,14 @@ func getContainerID(svc *ecs.Client, clusterName, taskArn, containerName string)
 			return *container.RuntimeId, nil
 		}
 	}
-	return "", fmt.Errorf("no container named %s found in task", containerName)
+	return "", fmt.Errorf("%w: %s", ErrContainerNotFound, containerName)
+}
+
+// taskIDFromArn extracts the short task ID from the end of a task ARN, which
+// is what the session-manager-plugin expects in an `ecs:` exec target.
+func taskIDFromArn(taskArn string) string {
+	parts := strings.Split(taskArn, "/")
+	return parts[len(parts)-1]
 }
 
 func startSSMSession(instanceID, containerID string, profile *string, region string) error {
@@ -119,17 +93,44 @@ func validateAWSCredentials(cfg aws.Config) error {
 }
 
 func main() {
-	clusterName := flag.String("cluster", "", "The ECS cluster name")
-	serviceName := flag.String("service", "", "The ECS service name")
-	containerName := flag.String("container", "", "The container name")
-	profile := flag.String("profile", "", "Optional AWS profile name")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "cp":
+			runCp(os.Args[2:])
+			return
+		case "run":
+			runRun(os.Args[2:])
+			return
+		}
+	}
+	runConnect(os.Args[1:])
+}
+
+// runConnect implements the default behaviour: resolve a task/container for
+// the given cluster and service, then open an interactive shell via the ECS
+// Exec or EC2-SSM path depending on --mode.
+func runConnect(args []string) {
+	fs := flag.NewFlagSet("docker-connector", flag.ExitOnError)
+	clusterName := fs.String("cluster", "", "The ECS cluster name (auto-discovered across the account if omitted)")
+	serviceName := fs.String("service", "", "The ECS service name (may be a glob, e.g. web-*, when --cluster is omitted)")
+	containerName := fs.String("container", "", "The container name (optional if the task has exactly one container)")
+	profile := fs.String("profile", "", "Optional AWS profile name")
+	mode := fs.String("mode", "auto", "Connection mode: auto, ssm (EC2 docker exec via SSM), or exec (native ECS ExecuteCommand, required for Fargate)")
+	taskFlag := fs.String("task", "", "Skip discovery and connect to this task ARN or ID directly")
+	pick := fs.Bool("pick", false, "Show an interactive picker when more than one task is running (default when stdin is a TTY)")
+	random := fs.Bool("random", false, "Opt in to the legacy behaviour of silently picking a random task")
+	refresh := fs.Bool("refresh", false, "Invalidate the cluster/service discovery cache before resolving --cluster")
+	retryCfg := retry.FlagConfig(fs)
 
-	flag.Parse()
+	fs.Parse(args)
 
-	if *serviceName == "" || *containerName == "" {
-		log.Fatal("Usage: docker-connector --cluster <cluster-name> --service <service-name> --container <container-name> [--profile <aws-profile>]")
+	if *serviceName == "" {
+		log.Fatal("Usage: docker-connector --service <service-name> [--cluster <cluster-name>] [--container <container-name>] [--profile <aws-profile>]")
 	}
 
+	explicitFlags := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicitFlags[f.Name] = true })
+
 	var cfg aws.Config
 	var err error
 	region := "eu-west-2"
@@ -148,67 +149,68 @@ func main() {
 
 	ecsClient := ecs.NewFromConfig(cfg)
 
-	maxRetries := 3
-	retrySuccess := false
-	backoffDelay := time.Second * 5
+	resolvedCluster, err := resolveClusterFlag(ecsClient, *clusterName, *serviceName, *refresh)
+	if err != nil {
+		log.Fatalf("Error resolving cluster: %v", err)
+	}
+	*clusterName = resolvedCluster
 
-	for i := 0; i < maxRetries; i++ {
-		taskArn, containerInstanceArn, err := getECSTask(ecsClient, *clusterName, *serviceName)
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stopSignals()
+
+	attempt := 0
+	err = retry.Do(ctx, *retryCfg, func() error {
+		attempt++
+
+		task, err := resolveTask(ecsClient, *clusterName, *serviceName, *taskFlag, *pick, *random, explicitFlags)
 		if err != nil {
-			if i == maxRetries-1 {
-				log.Fatalf("Error getting ECS task: %v. Maximum retries reached.", err)
-			} else {
-				log.Printf("No running tasks found for service. Retrying in %v...", backoffDelay)
-				time.Sleep(backoffDelay)
-				continue
-			}
+			return classifyAWSError(fmt.Errorf("resolving ECS task: %w", err))
 		}
-
+		taskArn := aws.ToString(task.TaskArn)
 		log.Printf("Found task ARN: %s\n", taskArn)
 
-		instanceID, err := getEC2InstanceID(ecsClient, *clusterName, containerInstanceArn)
+		resolvedContainer, err := resolveContainerName(task, *containerName)
 		if err != nil {
-			if i == maxRetries-1 {
-				log.Fatalf("Error getting EC2 instance ID: %v. Maximum retries reached.", err)
-			} else {
-				log.Printf("Error getting EC2 instance ID. Retrying in %v...", backoffDelay)
-				time.Sleep(backoffDelay)
-				continue
-			}
+			return retry.Terminal(err)
 		}
 
-		log.Printf("Found EC2 instance ID: %s\n", instanceID)
-
-		containerID, err := getContainerID(ecsClient, *clusterName, taskArn, *containerName)
+		containerID, err := getContainerID(ecsClient, *clusterName, taskArn, resolvedContainer)
 		if err != nil {
-			if i == maxRetries-1 {
-				log.Fatalf("Error getting container ID: %v. Maximum retries reached.", err)
-			} else {
-				log.Printf("Error getting container ID. Retrying in %v...", backoffDelay)
-				time.Sleep(backoffDelay)
-				continue
+			if errors.Is(err, ErrContainerNotFound) {
+				return retry.Terminal(err)
 			}
+			return classifyAWSError(fmt.Errorf("getting container ID: %w", err))
 		}
-
 		log.Printf("Found container ID: %s\n", containerID)
 
-		log.Printf("Attempting to start SSM session (Attempt %d/%d)...", i+1, maxRetries)
-		err = startSSMSession(instanceID, containerID, profile, region)
-		if err == nil {
-			log.Println("SSM session started successfully.")
-			retrySuccess = true
-			break
+		useExec := *mode == "exec" || (*mode == "auto" && task.LaunchType == types.LaunchTypeFargate)
+		if *mode == "ssm" && task.LaunchType == types.LaunchTypeFargate {
+			return retry.Terminal(fmt.Errorf("--mode ssm is not supported for Fargate tasks; use --mode exec instead"))
 		}
 
-		log.Printf("Failed to start SSM session: %v", err)
+		log.Printf("Attempting to start session (attempt %d)...", attempt)
+		if useExec {
+			if err := startECSExecSession(ecsClient, *clusterName, task, containerID, resolvedContainer, "bash", region, true); err != nil {
+				return classifyAWSError(fmt.Errorf("starting ECS Exec session: %w", err))
+			}
+			return nil
+		}
 
-		if i < maxRetries-1 {
-			log.Printf("Retrying with a new ECS task and container instance in %v...", backoffDelay)
-			time.Sleep(backoffDelay)
+		instanceID, err := getEC2InstanceID(ecsClient, *clusterName, aws.ToString(task.ContainerInstanceArn))
+		if err != nil {
+			return classifyAWSError(fmt.Errorf("getting EC2 instance ID: %w", err))
 		}
-	}
+		log.Printf("Found EC2 instance ID: %s\n", instanceID)
+		if err := startSSMSession(instanceID, containerID, profile, region); err != nil {
+			return fmt.Errorf("starting SSM session: %w", err)
+		}
+		return nil
+	}, func(err error, wait time.Duration) {
+		log.Printf("Attempt failed: %v. Retrying in %v...", err, wait)
+	})
 
-	if !retrySuccess {
-		log.Fatalf("Failed to start SSM session after %d attempts.", maxRetries)
+	if err != nil {
+		log.Fatalf("Failed to start session: %v", err)
 	}
+	log.Println("Session started successfully.")
 }
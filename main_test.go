@@ -0,0 +1,15 @@
+package main
+
+import "testing"
+
+func TestTaskIDFromArn(t *testing.T) {
+	cases := map[string]string{
+		"arn:aws:ecs:eu-west-2:123456789012:task/my-cluster/abc123def456": "abc123def456",
+		"abc123def456": "abc123def456",
+	}
+	for arn, want := range cases {
+		if got := taskIDFromArn(arn); got != want {
+			t.Errorf("taskIDFromArn(%q) = %q, want %q", arn, got, want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// resolveTask picks the task to connect to: --task skips discovery entirely,
+// otherwise it lists candidates and either prompts interactively or picks
+// one at random, depending on --pick/--random and whether stdin is a TTY.
+func resolveTask(svc *ecs.Client, clusterName, serviceName, taskFlag string, pick, random bool, explicitFlags map[string]bool) (types.Task, error) {
+	if taskFlag != "" {
+		return describeTaskByID(svc, clusterName, taskFlag)
+	}
+
+	tasks, err := listCandidateTasks(svc, clusterName, serviceName)
+	if err != nil {
+		return types.Task{}, err
+	}
+
+	usePick, useRandom := pick, random
+	if !explicitFlags["pick"] && !explicitFlags["random"] {
+		usePick = isInteractiveStdin() && len(tasks) > 1
+		useRandom = !usePick
+	}
+
+	if useRandom {
+		rand.Seed(time.Now().UnixNano())
+		return tasks[rand.Intn(len(tasks))], nil
+	}
+	if usePick {
+		return selectTask(tasks, bufio.NewReader(os.Stdin), os.Stdout)
+	}
+	return tasks[0], nil
+}
+
+// listCandidateTasks lists every running task for a service and describes
+// them in one batch, so callers have enough detail (status, health, AZ,
+// image) to show a picker without further round trips.
+func listCandidateTasks(svc *ecs.Client, clusterName, serviceName string) ([]types.Task, error) {
+	listInput := &ecs.ListTasksInput{
+		Cluster:       aws.String(clusterName),
+		ServiceName:   aws.String(serviceName),
+		DesiredStatus: types.DesiredStatusRunning,
+	}
+	listResult, err := svc.ListTasks(context.TODO(), listInput)
+	if err != nil || len(listResult.TaskArns) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoRunningTasks, serviceName)
+	}
+
+	describeInput := &ecs.DescribeTasksInput{
+		Cluster: aws.String(clusterName),
+		Tasks:   listResult.TaskArns,
+	}
+	describeResult, err := svc.DescribeTasks(context.TODO(), describeInput)
+	if err != nil || len(describeResult.Tasks) == 0 {
+		return nil, fmt.Errorf("could not describe running tasks for service %s", serviceName)
+	}
+	return describeResult.Tasks, nil
+}
+
+// describeTaskByID resolves a single task given an ARN or bare task ID,
+// bypassing service discovery entirely for the `--task` flag.
+func describeTaskByID(svc *ecs.Client, clusterName, taskIDOrArn string) (types.Task, error) {
+	describeInput := &ecs.DescribeTasksInput{
+		Cluster: aws.String(clusterName),
+		Tasks:   []string{taskIDOrArn},
+	}
+	describeResult, err := svc.DescribeTasks(context.TODO(), describeInput)
+	if err != nil || len(describeResult.Tasks) == 0 {
+		return types.Task{}, fmt.Errorf("could not find task %s in cluster %s", taskIDOrArn, clusterName)
+	}
+	return describeResult.Tasks[0], nil
+}
+
+// isInteractiveStdin reports whether stdin looks like a TTY, used to decide
+// whether the task picker should kick in by default.
+func isInteractiveStdin() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return (stat.Mode() & os.ModeCharDevice) != 0
+}
+
+// taskImageTag returns a comma-separated "container=image" summary (the full
+// image reference) for a task's containers, used for display in the picker
+// and as part of the search haystack in filterTasksBySearch.
+func taskImageTag(task types.Task) string {
+	images := make([]string, 0, len(task.Containers))
+	for _, c := range task.Containers {
+		if c.Name != nil && c.Image != nil {
+			images = append(images, fmt.Sprintf("%s=%s", *c.Name, *c.Image))
+		}
+	}
+	return strings.Join(images, ", ")
+}
+
+// selectTask shows a numbered list of candidate tasks and reads a selection
+// (or search query) from in, returning the chosen task. With a single
+// candidate it is returned without prompting.
+func selectTask(tasks []types.Task, in *bufio.Reader, out io.Writer) (types.Task, error) {
+	if len(tasks) == 1 {
+		return tasks[0], nil
+	}
+
+	for {
+		fmt.Fprintln(out, "Multiple running tasks found:")
+		for i, t := range tasks {
+			startedAt := "unknown"
+			if t.StartedAt != nil {
+				startedAt = t.StartedAt.Format("2006-01-02 15:04:05")
+			}
+			az := ""
+			if t.AvailabilityZone != nil {
+				az = *t.AvailabilityZone
+			}
+			health := string(t.HealthStatus)
+			fmt.Fprintf(out, "  [%d] %s  status=%s health=%s az=%s started=%s %s\n",
+				i+1, aws.ToString(t.TaskArn), aws.ToString(t.LastStatus), health, az, startedAt, taskImageTag(t))
+		}
+		fmt.Fprint(out, "Select a task number (or type a search string to filter): ")
+
+		line, err := in.ReadString('\n')
+		if err != nil {
+			return types.Task{}, fmt.Errorf("error reading selection: %w", err)
+		}
+		line = strings.TrimSpace(line)
+
+		if idx, err := strconv.Atoi(line); err == nil {
+			if idx < 1 || idx > len(tasks) {
+				fmt.Fprintln(out, "Selection out of range, try again.")
+				continue
+			}
+			return tasks[idx-1], nil
+		}
+
+		filtered := filterTasksBySearch(tasks, line)
+		if len(filtered) == 0 {
+			fmt.Fprintln(out, "No tasks matched that search, try again.")
+			continue
+		}
+		tasks = filtered
+		if len(tasks) == 1 {
+			return tasks[0], nil
+		}
+	}
+}
+
+// filterTasksBySearch narrows the candidate list to tasks whose ARN, status
+// or image tags contain the (case-insensitive) query.
+func filterTasksBySearch(tasks []types.Task, query string) []types.Task {
+	query = strings.ToLower(query)
+	var out []types.Task
+	for _, t := range tasks {
+		haystack := strings.ToLower(aws.ToString(t.TaskArn) + " " + taskImageTag(t) + " " + aws.ToString(t.LastStatus))
+		if strings.Contains(haystack, query) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// resolveContainerName returns containerName unchanged if set, otherwise
+// infers it from task when exactly one container is present.
+func resolveContainerName(task types.Task, containerName string) (string, error) {
+	if containerName != "" {
+		return containerName, nil
+	}
+	if len(task.Containers) == 1 && task.Containers[0].Name != nil {
+		return *task.Containers[0].Name, nil
+	}
+	names := make([]string, 0, len(task.Containers))
+	for _, c := range task.Containers {
+		if c.Name != nil {
+			names = append(names, *c.Name)
+		}
+	}
+	return "", fmt.Errorf("--container is required when a task has more than one container (found: %s)", strings.Join(names, ", "))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestResolveContainerName(t *testing.T) {
+	task := types.Task{Containers: []types.Container{
+		{Name: aws.String("web")},
+		{Name: aws.String("sidecar")},
+	}}
+
+	if _, err := resolveContainerName(task, ""); err == nil {
+		t.Fatalf("expected an error when --container is omitted and the task has multiple containers")
+	}
+	if got, err := resolveContainerName(task, "sidecar"); err != nil || got != "sidecar" {
+		t.Fatalf("resolveContainerName(task, %q) = %q, %v", "sidecar", got, err)
+	}
+
+	single := types.Task{Containers: []types.Container{{Name: aws.String("web")}}}
+	if got, err := resolveContainerName(single, ""); err != nil || got != "web" {
+		t.Fatalf("resolveContainerName(single-container task, \"\") = %q, %v, want %q, nil", got, err, "web")
+	}
+}
+
+func TestFilterTasksBySearch(t *testing.T) {
+	tasks := []types.Task{
+		{TaskArn: aws.String("arn:aws:ecs:eu-west-2:123:task/cluster/abc123"), LastStatus: aws.String("RUNNING")},
+		{TaskArn: aws.String("arn:aws:ecs:eu-west-2:123:task/cluster/def456"), LastStatus: aws.String("STOPPED")},
+	}
+
+	got := filterTasksBySearch(tasks, "abc123")
+	if len(got) != 1 || aws.ToString(got[0].TaskArn) != aws.ToString(tasks[0].TaskArn) {
+		t.Fatalf("filterTasksBySearch by ARN substring returned %+v", got)
+	}
+
+	got = filterTasksBySearch(tasks, "running")
+	if len(got) != 1 || aws.ToString(got[0].LastStatus) != "RUNNING" {
+		t.Fatalf("filterTasksBySearch by status (case-insensitive) returned %+v", got)
+	}
+
+	if got := filterTasksBySearch(tasks, "no-match"); len(got) != 0 {
+		t.Fatalf("filterTasksBySearch with no matches returned %+v, want empty", got)
+	}
+}
@@ -0,0 +1,122 @@
+// Package retry wraps cenkalti/backoff/v4 with the exponential backoff
+// policy and terminal/retryable error classification docker-connector uses
+// for its ECS discovery and session-start operations.
+package retry
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// Config holds the tunable exponential backoff parameters.
+type Config struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	MaxElapsedTime  time.Duration
+}
+
+// DefaultConfig mirrors the previous hard-coded retry loop's ballpark
+// behaviour: a 5s initial wait, doubling up to a minute, for up to 2 minutes
+// in total.
+func DefaultConfig() Config {
+	return Config{
+		InitialInterval: 5 * time.Second,
+		MaxInterval:     time.Minute,
+		Multiplier:      2.0,
+		MaxElapsedTime:  2 * time.Minute,
+	}
+}
+
+// FlagConfig registers --retry-initial, --retry-max, --retry-multiplier and
+// --retry-max-elapsed on fs, defaulting to the DOCKER_CONNECTOR_RETRY_*
+// environment variables (falling back to DefaultConfig). Call fs.Parse
+// before reading the returned Config.
+func FlagConfig(fs *flag.FlagSet) *Config {
+	def := DefaultConfig()
+	cfg := &Config{}
+	fs.DurationVar(&cfg.InitialInterval, "retry-initial", envDuration("DOCKER_CONNECTOR_RETRY_INITIAL", def.InitialInterval), "Initial retry backoff interval")
+	fs.DurationVar(&cfg.MaxInterval, "retry-max", envDuration("DOCKER_CONNECTOR_RETRY_MAX", def.MaxInterval), "Maximum retry backoff interval")
+	fs.Float64Var(&cfg.Multiplier, "retry-multiplier", envFloat("DOCKER_CONNECTOR_RETRY_MULTIPLIER", def.Multiplier), "Backoff multiplier applied between retries")
+	fs.DurationVar(&cfg.MaxElapsedTime, "retry-max-elapsed", envDuration("DOCKER_CONNECTOR_RETRY_MAX_ELAPSED", def.MaxElapsedTime), "Give up retrying after this long (0 = no limit)")
+	return cfg
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
+
+func envFloat(key string, fallback float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
+// terminalError marks an error that Do must not retry, e.g. bad
+// configuration or an authentication failure.
+type terminalError struct {
+	err error
+}
+
+func (e *terminalError) Error() string { return e.err.Error() }
+func (e *terminalError) Unwrap() error { return e.err }
+
+// Terminal marks err as non-retryable. Do stops immediately on such errors
+// instead of backing off and trying again.
+func Terminal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &terminalError{err: err}
+}
+
+// IsTerminal reports whether err (or one it wraps) was marked via Terminal.
+func IsTerminal(err error) bool {
+	var t *terminalError
+	return errors.As(err, &t)
+}
+
+// Notify is called after a retryable attempt fails, before the next backoff
+// sleep. wait is how long Do will sleep before the next attempt.
+type Notify func(err error, wait time.Duration)
+
+// Do runs op with exponential backoff per cfg. It stops immediately, without
+// sleeping, if op returns a Terminal error or ctx is cancelled (e.g. on
+// SIGINT) - in either case the triggering error is returned unwrapped.
+func Do(ctx context.Context, cfg Config, op func() error, notify Notify) error {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = cfg.InitialInterval
+	b.MaxInterval = cfg.MaxInterval
+	b.Multiplier = cfg.Multiplier
+	b.MaxElapsedTime = cfg.MaxElapsedTime
+
+	withCtx := backoff.WithContext(b, ctx)
+
+	return backoff.RetryNotify(func() error {
+		if err := op(); err != nil {
+			if IsTerminal(err) {
+				return backoff.Permanent(errors.Unwrap(err))
+			}
+			return err
+		}
+		return nil
+	}, withCtx, func(err error, wait time.Duration) {
+		if notify != nil {
+			notify(err, wait)
+		}
+	})
+}
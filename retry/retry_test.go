@@ -0,0 +1,93 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTerminalIsTerminal(t *testing.T) {
+	base := errors.New("boom")
+
+	if IsTerminal(base) {
+		t.Fatalf("plain error should not be terminal")
+	}
+
+	wrapped := Terminal(base)
+	if !IsTerminal(wrapped) {
+		t.Fatalf("Terminal(err) should be reported as terminal")
+	}
+	if !errors.Is(wrapped, base) {
+		t.Fatalf("Terminal(err) should still unwrap to the original error")
+	}
+
+	if Terminal(nil) != nil {
+		t.Fatalf("Terminal(nil) should return nil")
+	}
+}
+
+func TestDoStopsOnTerminalError(t *testing.T) {
+	sentinel := errors.New("bad config")
+	attempts := 0
+
+	cfg := Config{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 1, MaxElapsedTime: time.Second}
+	err := Do(context.Background(), cfg, func() error {
+		attempts++
+		return Terminal(sentinel)
+	}, nil)
+
+	if !errors.Is(err, sentinel) {
+		t.Fatalf("expected Do to return the terminal error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected exactly one attempt for a terminal error, got %d", attempts)
+	}
+}
+
+func TestDoStopsOnContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	cfg := Config{InitialInterval: time.Minute, MaxInterval: time.Minute, Multiplier: 2, MaxElapsedTime: 0}
+
+	start := time.Now()
+	err := Do(ctx, cfg, func() error {
+		attempts++
+		return errors.New("still failing")
+	}, nil)
+
+	if err == nil {
+		t.Fatalf("expected Do to return an error when the context is already cancelled")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Do should not have waited out the backoff after cancellation, took %v", elapsed)
+	}
+}
+
+func TestDoRetriesUntilSuccess(t *testing.T) {
+	attempts := 0
+	cfg := Config{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 1, MaxElapsedTime: time.Second}
+
+	var notified int
+	err := Do(context.Background(), cfg, func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("not ready yet")
+		}
+		return nil
+	}, func(err error, wait time.Duration) {
+		notified++
+	})
+
+	if err != nil {
+		t.Fatalf("expected Do to eventually succeed, got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+	if notified != 2 {
+		t.Fatalf("expected notify to fire once per failed attempt, got %d", notified)
+	}
+}
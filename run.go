@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+)
+
+const exitSentinelPrefix = "__EXIT_"
+
+// runRun implements `docker-connector run -- <cmd> [args...]`, a
+// non-interactive counterpart to the default interactive shell that streams
+// output and propagates the remote command's real exit code, for use from
+// scripts and CI.
+func runRun(args []string) {
+	fs := flag.NewFlagSet("docker-connector run", flag.ExitOnError)
+	clusterName := fs.String("cluster", "", "The ECS cluster name")
+	serviceName := fs.String("service", "", "The ECS service name")
+	containerName := fs.String("container", "", "The container name")
+	profile := fs.String("profile", "", "Optional AWS profile name")
+	allTasks := fs.Bool("all-tasks", false, "Run the command in every running task's container instead of just one")
+	fs.Parse(args)
+
+	if *serviceName == "" || *containerName == "" || fs.NArg() == 0 {
+		log.Fatal("Usage: docker-connector run [--cluster <cluster-name>] --service <service-name> --container <container-name> -- <cmd> [args...]")
+	}
+	command := strings.Join(fs.Args(), " ")
+
+	region := "eu-west-2"
+	var cfg aws.Config
+	var err error
+	if *profile != "" {
+		cfg, err = config.LoadDefaultConfig(context.TODO(), config.WithSharedConfigProfile(*profile), config.WithRegion(region))
+	} else {
+		cfg, err = config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	}
+	if err != nil {
+		log.Fatalf("Unable to load AWS config: %v", err)
+	}
+	if err := validateAWSCredentials(cfg); err != nil {
+		log.Fatalf("AWS authentication failed: %v", err)
+	}
+
+	ecsClient := ecs.NewFromConfig(cfg)
+
+	resolvedCluster, err := resolveClusterFlag(ecsClient, *clusterName, *serviceName, false)
+	if err != nil {
+		log.Fatalf("Error resolving cluster: %v", err)
+	}
+	*clusterName = resolvedCluster
+
+	taskArns, err := listRunningTaskArns(ecsClient, *clusterName, *serviceName, *allTasks)
+	if err != nil {
+		log.Fatalf("Error listing ECS tasks: %v", err)
+	}
+
+	exitCode := 0
+	for _, taskArn := range taskArns {
+		runtimeID, err := getContainerID(ecsClient, *clusterName, taskArn, *containerName)
+		if err != nil {
+			log.Fatalf("Error getting container ID for task %s: %v", taskArn, err)
+		}
+		taskID := taskIDFromArn(taskArn)
+
+		log.Printf("Running command in task %s...", taskID)
+		code, err := runOneShotCommand(ecsClient, *clusterName, taskArn, taskID, runtimeID, *containerName, command, region)
+		if err != nil {
+			log.Fatalf("Error running command in task %s: %v", taskID, err)
+		}
+		if code != 0 {
+			exitCode = code
+		}
+	}
+
+	os.Exit(exitCode)
+}
+
+// listRunningTaskArns returns the ARN of a single randomly-selected running
+// task, or every running task's ARN when allTasks is set. It goes through
+// the same resolveTask/listCandidateTasks discovery as the interactive
+// picker, rather than a second ad-hoc ListTasks call. The run subcommand has
+// no --task or --random flag: without allTasks the task is always picked at
+// random.
+func listRunningTaskArns(svc *ecs.Client, clusterName, serviceName string, allTasks bool) ([]string, error) {
+	if allTasks {
+		tasks, err := listCandidateTasks(svc, clusterName, serviceName)
+		if err != nil {
+			return nil, err
+		}
+		arns := make([]string, len(tasks))
+		for i, t := range tasks {
+			arns[i] = aws.ToString(t.TaskArn)
+		}
+		return arns, nil
+	}
+
+	task, err := resolveTask(svc, clusterName, serviceName, "", false, true, map[string]bool{"random": true})
+	if err != nil {
+		return nil, err
+	}
+	return []string{aws.ToString(task.TaskArn)}, nil
+}
+
+// runOneShotCommand executes command inside containerName via ECS Exec,
+// wrapping it with an exit-code sentinel so the real remote exit status can
+// be parsed out of the session-manager-plugin's stdout and propagated to the
+// caller, rather than always reporting success.
+func runOneShotCommand(svc *ecs.Client, clusterName, taskArn, taskID, runtimeID, containerName, command, region string) (int, error) {
+	wrapped := fmt.Sprintf("%s; echo %s$?__", command, exitSentinelPrefix)
+
+	stdout, _, wait, err := runCopyCommand(svc, clusterName, taskArn, taskID, runtimeID, containerName, wrapped, region)
+	if err != nil {
+		return -1, err
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	exitCode := -1
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, exitSentinelPrefix) && strings.HasSuffix(line, "__") {
+			codeStr := strings.TrimSuffix(strings.TrimPrefix(line, exitSentinelPrefix), "__")
+			if code, convErr := strconv.Atoi(codeStr); convErr == nil {
+				exitCode = code
+			}
+			continue
+		}
+		fmt.Println(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return -1, fmt.Errorf("error reading remote output: %w", err)
+	}
+	if exitCode == -1 {
+		return -1, fmt.Errorf("remote session ended without an exit-code sentinel")
+	}
+
+	wait()
+	return exitCode, nil
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+)
+
+// runCp implements `docker-connector cp <src> <dst>`, where exactly one of
+// src/dst is a `container:path` spec and the other a local path. With -r a
+// local directory is uploaded recursively via tar; recursive download is not
+// supported.
+func runCp(args []string) {
+	fs := flag.NewFlagSet("docker-connector cp", flag.ExitOnError)
+	clusterName := fs.String("cluster", "", "The ECS cluster name")
+	serviceName := fs.String("service", "", "The ECS service name")
+	profile := fs.String("profile", "", "Optional AWS profile name")
+	recursive := fs.Bool("r", false, "Copy directories recursively")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		log.Fatal("Usage: docker-connector cp [--cluster <cluster-name>] --service <service-name> [-r] <src> <dst>  (one of src/dst must be container:path)")
+	}
+	src := parseTransferSpec(fs.Arg(0))
+	dst := parseTransferSpec(fs.Arg(1))
+
+	var containerSpec, localPath string
+	upload := false
+	switch {
+	case src.Container == "" && dst.Container != "":
+		upload = true
+		localPath, containerSpec = src.Path, dst.Container
+	case src.Container != "" && dst.Container == "":
+		upload = false
+		containerSpec, localPath = src.Container, dst.Path
+	default:
+		log.Fatal("exactly one of src/dst must be a container:path spec")
+	}
+
+	region := "eu-west-2"
+	var cfg aws.Config
+	var err error
+	if *profile != "" {
+		cfg, err = config.LoadDefaultConfig(context.TODO(), config.WithSharedConfigProfile(*profile), config.WithRegion(region))
+	} else {
+		cfg, err = config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	}
+	if err != nil {
+		log.Fatalf("Unable to load AWS config: %v", err)
+	}
+	if err := validateAWSCredentials(cfg); err != nil {
+		log.Fatalf("AWS authentication failed: %v", err)
+	}
+
+	ecsClient := ecs.NewFromConfig(cfg)
+	resolvedCluster, err := resolveClusterFlag(ecsClient, *clusterName, *serviceName, false)
+	if err != nil {
+		log.Fatalf("Error resolving cluster: %v", err)
+	}
+	*clusterName = resolvedCluster
+
+	task, err := resolveTask(ecsClient, *clusterName, *serviceName, "", false, true, map[string]bool{"random": true})
+	if err != nil {
+		log.Fatalf("Error getting ECS task: %v", err)
+	}
+	taskArn := aws.ToString(task.TaskArn)
+	runtimeID, err := getContainerID(ecsClient, *clusterName, taskArn, containerSpec)
+	if err != nil {
+		log.Fatalf("Error getting container ID: %v", err)
+	}
+	taskID := taskIDFromArn(taskArn)
+
+	var remotePath string
+	if upload {
+		remotePath = dst.Path
+	} else {
+		remotePath = src.Path
+	}
+
+	if *recursive {
+		if !upload {
+			log.Fatal("recursive download is not yet supported; copy individual files instead")
+		}
+		err = cpUploadDir(ecsClient, *clusterName, taskArn, taskID, runtimeID, containerSpec, localPath, remotePath, region)
+	} else if upload {
+		err = cpUpload(ecsClient, *clusterName, taskArn, taskID, runtimeID, containerSpec, localPath, remotePath, region)
+	} else {
+		err = cpDownload(ecsClient, *clusterName, taskArn, taskID, runtimeID, containerSpec, remotePath, localPath, region)
+	}
+	if err != nil {
+		log.Fatalf("cp failed: %v", err)
+	}
+}
+
+// transferSpec describes one side of a `cp` invocation, e.g. "mycontainer:/var/log/app.log"
+// or a plain local path.
+type transferSpec struct {
+	Container string
+	Path      string
+}
+
+// parseTransferSpec splits a `container:path` argument into its parts. A spec
+// with no colon is treated as a local path.
+func parseTransferSpec(arg string) transferSpec {
+	if idx := strings.Index(arg, ":"); idx > 0 {
+		return transferSpec{Container: arg[:idx], Path: arg[idx+1:]}
+	}
+	return transferSpec{Path: arg}
+}
+
+// newEOFSentinel generates a per-transfer sentinel so the local side can
+// reliably detect the end of a base64 stream even if the remote payload
+// itself happens to contain the literal string.
+func newEOFSentinel() string {
+	return fmt.Sprintf("__EOF_%d__", rand.Int63())
+}
+
+// shellQuote wraps path in single quotes for safe interpolation into the
+// remote bash commands below, escaping any literal single quotes, so paths
+// containing spaces or shell metacharacters don't break the command.
+func shellQuote(path string) string {
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
+
+// runCopyCommand executes a command inside the container via ECS Exec and
+// hands back the raw session-manager-plugin stdout so callers can parse the
+// framed base64 response. It is the low-level primitive both cpUpload and
+// cpDownload are built on.
+func runCopyCommand(svc *ecs.Client, clusterName, taskArn, taskID, runtimeID, containerName, command, region string) (io.ReadCloser, io.WriteCloser, func() error, error) {
+	input := &ecs.ExecuteCommandInput{
+		Cluster:     aws.String(clusterName),
+		Task:        aws.String(taskArn),
+		Container:   aws.String(containerName),
+		Command:     aws.String(command),
+		Interactive: true,
+	}
+	result, err := svc.ExecuteCommand(context.TODO(), input)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("ecs:ExecuteCommand failed: %w", err)
+	}
+
+	sessionJSON, err := sessionManagerPluginArgs(result)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	target := execTarget(clusterName, taskID, runtimeID)
+	cmd := sessionManagerPluginCmd(sessionJSON, region, target)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not open session-manager-plugin stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not open session-manager-plugin stdout: %w", err)
+	}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, nil, nil, fmt.Errorf("could not start session-manager-plugin: %w", err)
+	}
+	return stdout, stdin, cmd.Wait, nil
+}
+
+// cpUpload pushes localPath into remotePath inside containerName by driving
+// `base64 -d > remotePath` through the exec session and writing the file to
+// stdin as one continuous base64 stream (no length prefixes or other
+// framing), then closing stdin. The remote shell answers with a completion
+// line made of the EOF sentinel and the sha256 of the written file, which is
+// checked against the local sum.
+func cpUpload(svc *ecs.Client, clusterName, taskArn, taskID, runtimeID, containerName, localPath, remotePath, region string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("could not open local file %s: %w", localPath, err)
+	}
+	defer f.Close()
+
+	sentinel := newEOFSentinel()
+	quotedPath := shellQuote(remotePath)
+	command := fmt.Sprintf(
+		"base64 -d > %s && echo %s_$(sha256sum %s | cut -d' ' -f1)",
+		quotedPath, sentinel, quotedPath,
+	)
+
+	stdout, stdin, wait, err := runCopyCommand(svc, clusterName, taskArn, taskID, runtimeID, containerName, command, region)
+	if err != nil {
+		return err
+	}
+
+	// The exec session's PTY echoes stdin back on stdout, so stdout must be
+	// drained concurrently with the stdin write below - otherwise, once the
+	// echoed data exceeds the session-manager-plugin's pipe buffer, it blocks
+	// writing to stdout, which stalls the remote side reading stdin, which
+	// deadlocks our own io.Copy.
+	sentinelResult := make(chan sentinelReadResult, 1)
+	go func() {
+		sum, err := readSentinelLine(stdout, sentinel)
+		sentinelResult <- sentinelReadResult{sum: sum, err: err}
+	}()
+
+	hasher := sha256.New()
+	tee := io.TeeReader(f, hasher)
+	encoder := base64.NewEncoder(base64.StdEncoding, stdin)
+
+	written, err := io.Copy(&progressWriter{w: encoder, label: fmt.Sprintf("uploading %s", localPath)}, tee)
+	if err != nil {
+		return fmt.Errorf("error streaming %s to container: %w", localPath, err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("error finalizing base64 stream: %w", err)
+	}
+	if err := stdin.Close(); err != nil {
+		return fmt.Errorf("error closing remote stdin: %w", err)
+	}
+
+	localSum := fmt.Sprintf("%x", hasher.Sum(nil))
+	result := <-sentinelResult
+	if result.err != nil {
+		return result.err
+	}
+	remoteSum := result.sum
+	if remoteSum != localSum {
+		return fmt.Errorf("checksum mismatch after upload: local=%s remote=%s", localSum, remoteSum)
+	}
+
+	fmt.Printf("uploaded %d bytes to %s:%s (sha256 verified)\n", written, containerName, remotePath)
+	return wait()
+}
+
+// cpDownload pulls remotePath out of containerName into localPath by driving
+// `base64 remotePath` through the exec session and decoding the framed
+// output locally, verifying a trailing sha256 the remote shell emits.
+func cpDownload(svc *ecs.Client, clusterName, taskArn, taskID, runtimeID, containerName, remotePath, localPath, region string) error {
+	sentinel := newEOFSentinel()
+	quotedPath := shellQuote(remotePath)
+	command := fmt.Sprintf(
+		"base64 %s; echo %s_$(sha256sum %s | cut -d' ' -f1)",
+		quotedPath, sentinel, quotedPath,
+	)
+
+	stdout, _, wait, err := runCopyCommand(svc, clusterName, taskArn, taskID, runtimeID, containerName, command, region)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(localPath)
+	if err != nil {
+		return fmt.Errorf("could not create local file %s: %w", localPath, err)
+	}
+	defer out.Close()
+
+	hasher := sha256.New()
+	progress := &progressWriter{w: out, label: fmt.Sprintf("downloading %s", localPath)}
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var remoteSum string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, sentinel+"_") {
+			remoteSum = strings.TrimPrefix(line, sentinel+"_")
+			break
+		}
+		decoded, decErr := base64.StdEncoding.DecodeString(line)
+		if decErr != nil {
+			continue
+		}
+		if _, err := progress.Write(decoded); err != nil {
+			return fmt.Errorf("error writing %s: %w", localPath, err)
+		}
+		hasher.Write(decoded)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading from container: %w", err)
+	}
+	if remoteSum == "" {
+		return fmt.Errorf("download stream ended without a checksum from the remote side")
+	}
+
+	localSum := fmt.Sprintf("%x", hasher.Sum(nil))
+	if remoteSum != localSum {
+		return fmt.Errorf("checksum mismatch after download: local=%s remote=%s", localSum, remoteSum)
+	}
+
+	fmt.Printf("downloaded %s:%s to %s (sha256 verified)\n", containerName, remotePath, localPath)
+	return wait()
+}
+
+// cpUploadDir tars localDir locally and pipes it into `tar -xf -` on the
+// remote side so directory copies don't need one round trip per file.
+func cpUploadDir(svc *ecs.Client, clusterName, taskArn, taskID, runtimeID, containerName, localDir, remoteDir, region string) error {
+	sentinel := newEOFSentinel()
+	quotedDir := shellQuote(remoteDir)
+	command := fmt.Sprintf("mkdir -p %s && base64 -d | tar -xf - -C %s && echo %s_done", quotedDir, quotedDir, sentinel)
+
+	stdout, stdin, wait, err := runCopyCommand(svc, clusterName, taskArn, taskID, runtimeID, containerName, command, region)
+	if err != nil {
+		return err
+	}
+
+	// Same PTY-echo deadlock as cpUpload: drain stdout concurrently with the
+	// stdin write instead of waiting until after it finishes.
+	sentinelResult := make(chan sentinelReadResult, 1)
+	go func() {
+		sum, err := readSentinelLine(stdout, sentinel)
+		sentinelResult <- sentinelReadResult{sum: sum, err: err}
+	}()
+
+	tarReader, tarErrCh := tarDirectory(localDir)
+	encoder := base64.NewEncoder(base64.StdEncoding, stdin)
+	written, copyErr := io.Copy(&progressWriter{w: encoder, label: fmt.Sprintf("uploading %s", localDir)}, tarReader)
+	if err := encoder.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	stdin.Close()
+	if tarErr := <-tarErrCh; tarErr != nil && copyErr == nil {
+		copyErr = tarErr
+	}
+	if copyErr != nil {
+		return fmt.Errorf("error streaming directory %s: %w", localDir, copyErr)
+	}
+
+	if result := <-sentinelResult; result.err != nil {
+		return result.err
+	}
+	fmt.Printf("uploaded %d bytes from %s to %s:%s\n", written, localDir, containerName, remoteDir)
+	return wait()
+}
+
+// sentinelReadResult carries readSentinelLine's outcome back from the
+// goroutine draining stdout concurrently with an in-flight upload.
+type sentinelReadResult struct {
+	sum string
+	err error
+}
+
+// readSentinelLine scans stdout until it sees a line prefixed with the given
+// sentinel and returns the suffix after the final underscore, used to pick
+// the sha256 checksum off of the completion line.
+func readSentinelLine(stdout io.Reader, sentinel string) (string, error) {
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, sentinel+"_") {
+			return strings.TrimPrefix(line, sentinel+"_"), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("error reading remote completion line: %w", err)
+	}
+	return "", fmt.Errorf("remote side closed the session without emitting a completion line")
+}
+
+// progressWriter wraps an io.Writer and prints a running byte count so long
+// transfers give the user some feedback.
+type progressWriter struct {
+	w     io.Writer
+	label string
+	total int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.total += int64(n)
+	fmt.Printf("\r%s: %d bytes", p.label, p.total)
+	return n, err
+}
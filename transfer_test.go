@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestParseTransferSpec(t *testing.T) {
+	cases := []struct {
+		arg  string
+		want transferSpec
+	}{
+		{"myapp:/var/log/app.log", transferSpec{Container: "myapp", Path: "/var/log/app.log"}},
+		{"/local/path", transferSpec{Path: "/local/path"}},
+		{":/leading/colon", transferSpec{Path: ":/leading/colon"}},
+	}
+	for _, c := range cases {
+		got := parseTransferSpec(c.arg)
+		if got != c.want {
+			t.Errorf("parseTransferSpec(%q) = %+v, want %+v", c.arg, got, c.want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/var/log/app.log", "'/var/log/app.log'"},
+		{"/var/log/app 2.log", "'/var/log/app 2.log'"},
+		{"/tmp/it's.txt", `'/tmp/it'\''s.txt'`},
+	}
+	for _, c := range cases {
+		if got := shellQuote(c.path); got != c.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", c.path, got, c.want)
+		}
+	}
+}